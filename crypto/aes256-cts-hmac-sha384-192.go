@@ -14,7 +14,12 @@ import (
 
 // RFC https://tools.ietf.org/html/rfc8009
 
-// Aes256CtsHmacSha384192 implements Kerberos encryption type aes256-cts-hmac-sha384-192
+// Aes256CtsHmacSha384192 implements Kerberos encryption type aes256-cts-hmac-sha384-192.
+// The AES, HMAC and SHA-384 primitives it uses are constructed inside the
+// crypto/rfc8009 and crypto/common packages it delegates to, which obtain
+// them from the registered crypto/provider.Provider, so a FIPS/PKCS#11
+// backend registered via provider.SetProvider takes effect for this etype.
+// The HMAC inside PBKDF2 string-to-key is golang.org/x/crypto/pbkdf2's own.
 type Aes256CtsHmacSha384192 struct {
 }
 
@@ -101,7 +106,7 @@ func (e Aes256CtsHmacSha384192) DecryptMessage(key, ciphertext []byte, usage uin
 
 // DeriveKey derives a key from the protocol key based on the usage value.
 func (e Aes256CtsHmacSha384192) DeriveKey(protocolKey, usage []byte) ([]byte, error) {
-	return rfc8009.DeriveKey(protocolKey, usage, e), nil
+	return rfc8009.DeriveKey(protocolKey, usage, e)
 }
 
 // DeriveRandom generates data needed for key generation.
@@ -0,0 +1,28 @@
+// Package common holds checksum helpers shared by the RFC 8009 AES-CTS-HMAC-SHA2
+// etypes, which key and truncate their HMAC-SHA2 checksums identically.
+package common
+
+import (
+	"gopkg.in/burningass23/gokrb5.v7/crypto/etype"
+	"gopkg.in/burningass23/gokrb5.v7/crypto/provider"
+)
+
+// GetUsageKc returns the checksum key-usage suffix bytes for usage, per the
+// RFC 3961 section 5 convention (the usage number followed by 0x99).
+func GetUsageKc(usage uint32) []byte {
+	return []byte{byte(usage >> 24), byte(usage >> 16), byte(usage >> 8), byte(usage), 0x99}
+}
+
+// GetHash returns the HMAC-SHA2 checksum of data, keyed with the checksum
+// sub-key derived from protocolKey and usage, truncated to e's HMAC length.
+// The HMAC primitive is obtained from the currently registered
+// crypto/provider.Provider.
+func GetHash(data, protocolKey, usage []byte, e etype.EType) ([]byte, error) {
+	kc, err := e.DeriveKey(protocolKey, usage)
+	if err != nil {
+		return nil, err
+	}
+	h := provider.Current().NewHMAC(kc, e.GetHashFunc())
+	h.Write(data)
+	return h.Sum(nil)[:e.GetHMACBitLength()/8], nil
+}
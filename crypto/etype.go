@@ -0,0 +1,28 @@
+package crypto
+
+import (
+	"fmt"
+
+	"gopkg.in/burningass23/gokrb5.v7/crypto/etype"
+	"gopkg.in/burningass23/gokrb5.v7/crypto/provider"
+	"gopkg.in/burningass23/gokrb5.v7/iana/etypeID"
+)
+
+// GetEtype returns an instance of the required etype struct for the etype ID
+// specified. If FIPS mode has been enabled via provider.SetFIPSMode, an etype
+// ID that is not on the FIPS-approved list is rejected here rather than
+// being handed back to the caller for ticket processing.
+func GetEtype(id int32) (etype.EType, error) {
+	if err := provider.CheckAllowed(id); err != nil {
+		return nil, err
+	}
+	switch id {
+	case etypeID.AES256_CTS_HMAC_SHA384_192:
+		return Aes256CtsHmacSha384192{}, nil
+	case etypeID.CAMELLIA128_CTS_CMAC:
+		return Camellia128CtsCmac{}, nil
+	case etypeID.CAMELLIA256_CTS_CMAC:
+		return Camellia256CtsCmac{}, nil
+	}
+	return nil, fmt.Errorf("unknown or unsupported etype ID: %d", id)
+}
@@ -0,0 +1,29 @@
+// Package etype defines the interface that Kerberos encryption type implementations must satisfy.
+package etype
+
+import "hash"
+
+// EType is the interface that a Kerberos encryption and checksum type implementation must satisfy.
+type EType interface {
+	GetETypeID() int32
+	GetHashID() int32
+	GetKeyByteSize() int
+	GetKeySeedBitLength() int
+	GetHashFunc() func() hash.Hash
+	GetMessageBlockByteSize() int
+	GetDefaultStringToKeyParams() string
+	GetConfounderByteSize() int
+	GetHMACBitLength() int
+	GetCypherBlockBitLength() int
+	StringToKey(secret string, salt string, s2kparams string) ([]byte, error)
+	RandomToKey(b []byte) []byte
+	EncryptData(key, data []byte) ([]byte, []byte, error)
+	EncryptMessage(key, message []byte, usage uint32) ([]byte, []byte, error)
+	DecryptData(key, data []byte) ([]byte, error)
+	DecryptMessage(key, ciphertext []byte, usage uint32) ([]byte, error)
+	DeriveKey(protocolKey, usage []byte) ([]byte, error)
+	DeriveRandom(protocolKey, usage []byte) ([]byte, error)
+	VerifyIntegrity(protocolKey, ct, pt []byte, usage uint32) bool
+	GetChecksumHash(protocolKey, data []byte, usage uint32) ([]byte, error)
+	VerifyChecksum(protocolKey, data, chksum []byte, usage uint32) bool
+}
@@ -0,0 +1,50 @@
+package provider
+
+import (
+	"fmt"
+	"sync/atomic"
+
+	"gopkg.in/burningass23/gokrb5.v7/iana/etypeID"
+)
+
+// fipsMode is non-zero once SetFIPSMode(true) has been called, enforcing that
+// only approvedEtypes may be used to process tickets.
+var fipsMode int32
+
+// approvedEtypes are the etype IDs considered FIPS-140 approved: the
+// AES-SHA2 (RFC 8009) family whose primitives route through the registered
+// Provider. DES, RC4 and the legacy AES-SHA1 etypes are not approved, and
+// neither are the RFC 6803 Camellia/CMAC etypes since Camellia is not a
+// FIPS-approved algorithm.
+var approvedEtypes = map[int32]bool{
+	etypeID.AES128_CTS_HMAC_SHA256_128: true,
+	etypeID.AES256_CTS_HMAC_SHA384_192: true,
+}
+
+// SetFIPSMode enables or disables FIPS-only enforcement. While enabled,
+// CheckAllowed rejects any etype ID not in approvedEtypes.
+func SetFIPSMode(enabled bool) {
+	if enabled {
+		atomic.StoreInt32(&fipsMode, 1)
+		return
+	}
+	atomic.StoreInt32(&fipsMode, 0)
+}
+
+// FIPSMode reports whether FIPS-only enforcement is currently enabled.
+func FIPSMode() bool {
+	return atomic.LoadInt32(&fipsMode) != 0
+}
+
+// CheckAllowed returns an error if FIPS mode is enabled and id is not an
+// approved etype. Callers processing a ticket or key should call this before
+// using the etype so non-approved keys are rejected rather than silently used.
+func CheckAllowed(id int32) error {
+	if !FIPSMode() {
+		return nil
+	}
+	if approvedEtypes[id] {
+		return nil
+	}
+	return fmt.Errorf("etype ID %d is not approved for use in FIPS mode", id)
+}
@@ -0,0 +1,67 @@
+// Package provider lets deployments swap the AES, HMAC, SHA and RNG
+// primitives that Kerberos etype implementations use for a validated backend
+// (e.g. BoringCrypto or a PKCS#11 HSM), as required in FIPS-140 environments.
+package provider
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/rand"
+	"hash"
+	"io"
+	"sync"
+)
+
+// Provider supplies the cryptographic primitives an etype implementation
+// needs. Implementations must be safe for concurrent use.
+type Provider interface {
+	// NewAESCipher returns an AES block cipher initialised with key.
+	NewAESCipher(key []byte) (cipher.Block, error)
+	// NewHMAC returns an HMAC keyed with key, using the hash function h.
+	NewHMAC(key []byte, h func() hash.Hash) hash.Hash
+	// NewHash returns an unkeyed instance of the hash function h.
+	NewHash(h func() hash.Hash) hash.Hash
+	// Rand returns the source of randomness to use for key and confounder generation.
+	Rand() io.Reader
+}
+
+// stdlibProvider is the default Provider, backed directly by the Go standard
+// library crypto/aes, crypto/hmac and crypto/rand packages.
+type stdlibProvider struct{}
+
+func (stdlibProvider) NewAESCipher(key []byte) (cipher.Block, error) {
+	return aes.NewCipher(key)
+}
+
+func (stdlibProvider) NewHMAC(key []byte, h func() hash.Hash) hash.Hash {
+	return hmac.New(h, key)
+}
+
+func (stdlibProvider) NewHash(h func() hash.Hash) hash.Hash {
+	return h()
+}
+
+func (stdlibProvider) Rand() io.Reader {
+	return rand.Reader
+}
+
+var (
+	mu      sync.RWMutex
+	current Provider = stdlibProvider{}
+)
+
+// SetProvider registers p as the Provider used by etype implementations for
+// all subsequent cryptographic operations.
+func SetProvider(p Provider) {
+	mu.Lock()
+	defer mu.Unlock()
+	current = p
+}
+
+// Current returns the currently registered Provider.
+func Current() Provider {
+	mu.RLock()
+	defer mu.RUnlock()
+	return current
+}
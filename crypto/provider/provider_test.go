@@ -0,0 +1,102 @@
+package provider
+
+import (
+	"bytes"
+	"crypto/cipher"
+	"crypto/sha256"
+	"hash"
+	"io"
+	"testing"
+
+	"gopkg.in/burningass23/gokrb5.v7/iana/etypeID"
+)
+
+// stubProvider is a Provider that records whether it was invoked, letting
+// tests assert that a registered Provider is actually consulted rather than
+// the stdlib falling through silently.
+type stubProvider struct {
+	aesCipherCalled bool
+	hmacCalled      bool
+	hashCalled      bool
+}
+
+func (s *stubProvider) NewAESCipher(key []byte) (cipher.Block, error) {
+	s.aesCipherCalled = true
+	return stdlibProvider{}.NewAESCipher(key)
+}
+
+func (s *stubProvider) NewHMAC(key []byte, h func() hash.Hash) hash.Hash {
+	s.hmacCalled = true
+	return stdlibProvider{}.NewHMAC(key, h)
+}
+
+func (s *stubProvider) NewHash(h func() hash.Hash) hash.Hash {
+	s.hashCalled = true
+	return stdlibProvider{}.NewHash(h)
+}
+
+func (s *stubProvider) Rand() io.Reader {
+	return stdlibProvider{}.Rand()
+}
+
+func TestSetProviderAndCurrent(t *testing.T) {
+	t.Cleanup(func() { SetProvider(stdlibProvider{}) })
+
+	if _, ok := Current().(stdlibProvider); !ok {
+		t.Fatalf("Current() before any SetProvider call = %T, want stdlibProvider", Current())
+	}
+
+	s := &stubProvider{}
+	SetProvider(s)
+	if Current() != Provider(s) {
+		t.Fatal("Current() did not return the provider registered by SetProvider")
+	}
+
+	if _, err := Current().NewAESCipher(bytes.Repeat([]byte{1}, 16)); err != nil {
+		t.Fatal(err)
+	}
+	Current().NewHMAC([]byte("key"), sha256.New)
+	Current().NewHash(sha256.New)
+	if !s.aesCipherCalled || !s.hmacCalled || !s.hashCalled {
+		t.Fatalf("swapped provider was not invoked: aes=%v hmac=%v hash=%v", s.aesCipherCalled, s.hmacCalled, s.hashCalled)
+	}
+}
+
+func TestFIPSModeRejectsNonApprovedEtypes(t *testing.T) {
+	t.Cleanup(func() { SetFIPSMode(false) })
+
+	nonApproved := []int32{
+		etypeID.DES_CBC_CRC,
+		etypeID.RC4_HMAC,
+		etypeID.AES128_CTS_HMAC_SHA1_96,
+		etypeID.AES256_CTS_HMAC_SHA1_96,
+		etypeID.CAMELLIA128_CTS_CMAC,
+		etypeID.CAMELLIA256_CTS_CMAC,
+	}
+
+	SetFIPSMode(false)
+	if FIPSMode() {
+		t.Fatal("FIPSMode() true before SetFIPSMode(true) was called")
+	}
+	for _, id := range nonApproved {
+		if err := CheckAllowed(id); err != nil {
+			t.Fatalf("CheckAllowed(%d) = %v with FIPS mode off, want nil", id, err)
+		}
+	}
+
+	SetFIPSMode(true)
+	if !FIPSMode() {
+		t.Fatal("FIPSMode() false after SetFIPSMode(true)")
+	}
+	for _, id := range nonApproved {
+		if err := CheckAllowed(id); err == nil {
+			t.Errorf("CheckAllowed(%d) = nil in FIPS mode, want a rejection error", id)
+		}
+	}
+
+	for _, id := range []int32{etypeID.AES128_CTS_HMAC_SHA256_128, etypeID.AES256_CTS_HMAC_SHA384_192} {
+		if err := CheckAllowed(id); err != nil {
+			t.Errorf("CheckAllowed(%d) = %v in FIPS mode, want nil (approved etype)", id, err)
+		}
+	}
+}
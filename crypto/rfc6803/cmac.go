@@ -0,0 +1,81 @@
+package rfc6803
+
+import "crypto/cipher"
+
+// cmac computes the CMAC (RFC 4493) of msg under the block cipher c. RFC 6803
+// reuses this construction over Camellia in place of HMAC for both checksums
+// and key derivation (PRF+).
+func cmac(c cipher.Block, msg []byte) []byte {
+	bs := c.BlockSize()
+	k1, k2 := subKeys(c)
+
+	var nBlocks int
+	var lastComplete bool
+	if len(msg) == 0 {
+		nBlocks = 1
+		lastComplete = false
+	} else {
+		nBlocks = (len(msg) + bs - 1) / bs
+		lastComplete = len(msg)%bs == 0
+	}
+
+	mLast := make([]byte, bs)
+	if lastComplete {
+		copy(mLast, msg[(nBlocks-1)*bs:])
+		xorInto(mLast, k1)
+	} else {
+		copy(mLast, msg[(nBlocks-1)*bs:])
+		mLast[len(msg)-(nBlocks-1)*bs] = 0x80
+		xorInto(mLast, k2)
+	}
+
+	x := make([]byte, bs)
+	y := make([]byte, bs)
+	for i := 0; i < nBlocks-1; i++ {
+		copy(y, msg[i*bs:(i+1)*bs])
+		xorInto(y, x)
+		c.Encrypt(x, y)
+	}
+	copy(y, mLast)
+	xorInto(y, x)
+	c.Encrypt(x, y)
+	return x
+}
+
+// subKeys derives the CMAC subkeys K1 and K2 from the block cipher per RFC 4493 section 2.3.
+func subKeys(c cipher.Block) (k1, k2 []byte) {
+	bs := c.BlockSize()
+	l := make([]byte, bs)
+	c.Encrypt(l, make([]byte, bs))
+
+	k1 = leftShiftAndXorRb(l)
+	k2 = leftShiftAndXorRb(k1)
+	return
+}
+
+// leftShiftAndXorRb left shifts b by one bit and conditionally XORs in the
+// Rb constant for a 128-bit block (0x87), as defined by RFC 4493 section 2.3.
+func leftShiftAndXorRb(b []byte) []byte {
+	msb := b[0]&0x80 != 0
+	out := leftShift(b)
+	if msb {
+		out[len(out)-1] ^= 0x87
+	}
+	return out
+}
+
+func leftShift(b []byte) []byte {
+	out := make([]byte, len(b))
+	var carry byte
+	for i := len(b) - 1; i >= 0; i-- {
+		out[i] = b[i]<<1 | carry
+		carry = b[i] >> 7
+	}
+	return out
+}
+
+func xorInto(dst, src []byte) {
+	for i := range dst {
+		dst[i] ^= src[i]
+	}
+}
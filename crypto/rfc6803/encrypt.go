@@ -0,0 +1,257 @@
+package rfc6803
+
+import (
+	"crypto/cipher"
+	"crypto/hmac"
+	"errors"
+	"io"
+
+	"golang.org/x/crypto/camellia"
+
+	"gopkg.in/burningass23/gokrb5.v7/crypto/etype"
+	"gopkg.in/burningass23/gokrb5.v7/crypto/provider"
+)
+
+const (
+	// keyUsageEncrypt and keyUsageIntegrity are the RFC 3961 derived-key
+	// usage suffixes for the encryption and checksum sub-keys (Ke, Ki).
+	keyUsageEncrypt   = 0xAA
+	keyUsageIntegrity = 0x55
+)
+
+// EncryptData encrypts the data provided directly with key using CBC-CTS mode
+// over Camellia (no key derivation or confounder, unlike EncryptMessage),
+// returning the IV and the ciphertext.
+func EncryptData(key, data []byte, e etype.EType) ([]byte, []byte, error) {
+	c, err := camellia.NewCipher(key)
+	if err != nil {
+		return nil, nil, err
+	}
+	iv := make([]byte, c.BlockSize())
+	ct, err := ctsEncrypt(c, iv, data)
+	return iv, ct, err
+}
+
+// EncryptMessage encrypts message (prefixed with a random confounder) and
+// appends the CMAC integrity hash, returning the IV and the full encrypted message.
+func EncryptMessage(key, message []byte, usage uint32, e etype.EType) ([]byte, []byte, error) {
+	conf := make([]byte, e.GetConfounderByteSize())
+	if _, err := io.ReadFull(provider.Current().Rand(), conf); err != nil {
+		return nil, nil, err
+	}
+	pt := append(conf, message...)
+
+	ke, err := DeriveKey(key, append(usageBytes(usage), keyUsageEncrypt), e)
+	if err != nil {
+		return nil, nil, err
+	}
+	c, err := camellia.NewCipher(ke)
+	if err != nil {
+		return nil, nil, err
+	}
+	iv := make([]byte, c.BlockSize())
+	ct, err := ctsEncrypt(c, iv, pt)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	ki, err := DeriveKey(key, append(usageBytes(usage), keyUsageIntegrity), e)
+	if err != nil {
+		return nil, nil, err
+	}
+	kic, err := camellia.NewCipher(ki)
+	if err != nil {
+		return nil, nil, err
+	}
+	// RFC 6803 follows the RFC 3961 simplified profile: the checksum is taken
+	// over the unpadded confounder || message *before* encryption, not over
+	// the ciphertext and not over the CTS padding (CMAC's own last-block
+	// handling already covers non-block-aligned lengths; zero-padding first
+	// would compute a different, non-interoperable tag).
+	h := cmac(kic, pt)
+
+	return iv, append(ct, h...), nil
+}
+
+// DecryptData reverses EncryptData.
+func DecryptData(key, data []byte, e etype.EType) ([]byte, error) {
+	c, err := camellia.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	iv := make([]byte, c.BlockSize())
+	return ctsDecrypt(c, iv, data)
+}
+
+// DecryptMessage decrypts ciphertext produced by EncryptMessage, verifies its
+// CMAC integrity hash and strips the leading confounder, returning the plaintext message.
+func DecryptMessage(key, ciphertext []byte, usage uint32, e etype.EType) ([]byte, error) {
+	hLen := e.GetHMACBitLength() / 8
+	if len(ciphertext) < hLen+e.GetConfounderByteSize() {
+		return nil, errors.New("ciphertext is too short")
+	}
+	ct := ciphertext[:len(ciphertext)-hLen]
+	h := ciphertext[len(ciphertext)-hLen:]
+
+	ke, err := DeriveKey(key, append(usageBytes(usage), keyUsageEncrypt), e)
+	if err != nil {
+		return nil, err
+	}
+	c, err := camellia.NewCipher(ke)
+	if err != nil {
+		return nil, err
+	}
+	iv := make([]byte, c.BlockSize())
+	pt, err := ctsDecrypt(c, iv, ct)
+	if err != nil {
+		return nil, err
+	}
+
+	ki, err := DeriveKey(key, append(usageBytes(usage), keyUsageIntegrity), e)
+	if err != nil {
+		return nil, err
+	}
+	kic, err := camellia.NewCipher(ki)
+	if err != nil {
+		return nil, err
+	}
+	// The checksum covers the recovered, unpadded confounder || message, not the ciphertext.
+	if !hmac.Equal(h, cmac(kic, pt)) {
+		return nil, errors.New("integrity check failed")
+	}
+
+	if len(pt) < e.GetConfounderByteSize() {
+		return nil, errors.New("decrypted plaintext is shorter than the confounder")
+	}
+	return pt[e.GetConfounderByteSize():], nil
+}
+
+// VerifyIntegrity checks the CMAC integrity hash appended to ciphertext
+// against the already-decrypted plaintext pt (the unpadded confounder ||
+// message the checksum was taken over), per the RFC 3961 simplified profile.
+func VerifyIntegrity(protocolKey, ciphertext, pt []byte, usage uint32, e etype.EType) bool {
+	hLen := e.GetHMACBitLength() / 8
+	if len(ciphertext) < hLen {
+		return false
+	}
+	h := ciphertext[len(ciphertext)-hLen:]
+
+	ki, err := DeriveKey(protocolKey, append(usageBytes(usage), keyUsageIntegrity), e)
+	if err != nil {
+		return false
+	}
+	kic, err := camellia.NewCipher(ki)
+	if err != nil {
+		return false
+	}
+	return hmac.Equal(h, cmac(kic, pt))
+}
+
+// ctsEncrypt encrypts pt with CBC, applying ciphertext stealing (the CS3
+// variant defined in NIST SP 800-38A's CBC-CS3 addendum and used throughout
+// Kerberos, per RFC 3962 Appendix A) so pt need not be a multiple of the
+// cipher's block size. Blocks before the final pair chain normally; the
+// final two blocks, Pn-1 (full) and Pn (length tailLen, 1<=tailLen<=bs), are
+// chained as in CBC, with Pn zero-padded to a full block, and then placed in
+// swapped, truncated order:
+//
+//	En-1 = Encrypt(Pn-1 XOR prev)
+//	Dn   = (Pn || zeros) XOR En-1
+//	Cn-1 = Encrypt(Dn)
+//	Cn   = En-1[:tailLen]
+//	ciphertext = ..., Cn-1, Cn
+//
+// The last two blocks are always swapped this way, even when len(pt) is an
+// exact multiple of the block size (RFC 3962 Appendix B's 32-byte test
+// vector exercises exactly this case).
+func ctsEncrypt(c cipher.Block, iv, pt []byte) ([]byte, error) {
+	bs := c.BlockSize()
+	if len(pt) < bs {
+		return nil, errors.New("plaintext is shorter than the cipher block size")
+	}
+	if len(pt) == bs {
+		ct := make([]byte, bs)
+		cipher.NewCBCEncrypter(c, iv).CryptBlocks(ct, pt)
+		return ct, nil
+	}
+
+	tailLen := len(pt) % bs
+	if tailLen == 0 {
+		tailLen = bs
+	}
+	body := pt[:len(pt)-bs-tailLen]
+	pn1 := pt[len(pt)-bs-tailLen : len(pt)-tailLen]
+	pn := pt[len(pt)-tailLen:]
+
+	prev := iv
+	out := make([]byte, 0, len(pt))
+	if len(body) > 0 {
+		bodyCT := make([]byte, len(body))
+		cipher.NewCBCEncrypter(c, iv).CryptBlocks(bodyCT, body)
+		out = append(out, bodyCT...)
+		prev = bodyCT[len(bodyCT)-bs:]
+	}
+
+	en1In := append([]byte{}, pn1...)
+	xorInto(en1In, prev)
+	en1 := make([]byte, bs)
+	c.Encrypt(en1, en1In)
+
+	dn := make([]byte, bs)
+	copy(dn, pn)
+	xorInto(dn, en1)
+	cn1 := make([]byte, bs)
+	c.Encrypt(cn1, dn)
+
+	out = append(out, cn1...)
+	out = append(out, en1[:tailLen]...)
+	return out, nil
+}
+
+// ctsDecrypt reverses ctsEncrypt.
+func ctsDecrypt(c cipher.Block, iv, ct []byte) ([]byte, error) {
+	bs := c.BlockSize()
+	if len(ct) < bs {
+		return nil, errors.New("ciphertext is shorter than the cipher block size")
+	}
+	if len(ct) == bs {
+		pt := make([]byte, len(ct))
+		cipher.NewCBCDecrypter(c, iv).CryptBlocks(pt, ct)
+		return pt, nil
+	}
+
+	n := len(ct)
+	tailLen := n % bs
+	if tailLen == 0 {
+		tailLen = bs
+	}
+	body := ct[:n-bs-tailLen]
+	cn1 := ct[n-bs-tailLen : n-tailLen]
+	cn := ct[n-tailLen:]
+
+	prev := iv
+	var bodyPT []byte
+	if len(body) > 0 {
+		bodyPT = make([]byte, len(body))
+		cipher.NewCBCDecrypter(c, iv).CryptBlocks(bodyPT, body)
+		prev = body[len(body)-bs:]
+	}
+
+	dn := make([]byte, bs)
+	c.Decrypt(dn, cn1)
+	pn := append([]byte{}, dn[:tailLen]...)
+	xorInto(pn, cn)
+
+	en1 := append(append([]byte{}, cn...), dn[tailLen:]...)
+	pn1 := make([]byte, bs)
+	c.Decrypt(pn1, en1)
+	xorInto(pn1, prev)
+
+	pt := append(bodyPT, pn1...)
+	pt = append(pt, pn...)
+	return pt, nil
+}
+
+func usageBytes(usage uint32) []byte {
+	return []byte{byte(usage >> 24), byte(usage >> 16), byte(usage >> 8), byte(usage)}
+}
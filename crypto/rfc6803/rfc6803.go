@@ -0,0 +1,135 @@
+// Package rfc6803 implements the Camellia encryption and CMAC checksum types
+// for Kerberos 5 defined in RFC 6803: camellia128-cts-cmac, camellia256-cts-cmac,
+// cmac-camellia128 and cmac-camellia256.
+//
+// Unlike RFC 3961's n-fold-based DR, RFC 6803 section 3 derives keys with the
+// SP800-108 KDF in feedback mode, using CMAC (RFC 4493) as the PRF:
+// K(i) = CMAC(key, K(i-1) || i (4-octet big-endian counter) || label || 0x00),
+// with K(0) the empty string. random-to-key is the identity function, so a
+// Camellia key is simply the raw key bytes.
+//
+// Camellia cipher construction is not routed through crypto/provider: Camellia
+// is not a FIPS-140 approved algorithm, so it is deliberately excluded from
+// provider.Provider's scope (see the approvedEtypes comment in
+// crypto/provider/fips.go) and reference implementations such as PKCS11Provider
+// do not support it. Only randomness generation, which is algorithm-agnostic,
+// is sourced from the registered provider.
+package rfc6803
+
+import (
+	"crypto/sha1"
+	"errors"
+
+	"golang.org/x/crypto/camellia"
+	"golang.org/x/crypto/pbkdf2"
+
+	"gopkg.in/burningass23/gokrb5.v7/crypto/etype"
+)
+
+const (
+	kerberosConstant = "kerberos"
+)
+
+// StringToKey returns a key derived from the string provided, per RFC 6803
+// section 6. Unlike RFC 8009, RFC 6803 does not prefix the salt with the
+// etype name; the bare salt is passed to PBKDF2 as-is.
+func StringToKey(secret, salt, s2kparams string, e etype.EType) ([]byte, error) {
+	i, err := iterations(s2kparams)
+	if err != nil {
+		return nil, err
+	}
+	tkey := pbkdf2.Key([]byte(secret), []byte(salt), i, e.GetKeyByteSize(), sha1.New)
+	return DeriveKey(tkey, []byte(kerberosConstant), e)
+}
+
+// RandomToKey returns a key from the bytes provided. RFC 6803 defines
+// random-to-key as the identity function.
+func RandomToKey(b []byte) []byte {
+	return b
+}
+
+// DeriveKey derives a key from the protocol key and usage value (DK in RFC 3961/6803).
+func DeriveKey(protocolKey, usage []byte, e etype.EType) ([]byte, error) {
+	r, err := DeriveRandom(protocolKey, usage, e)
+	if err != nil {
+		return nil, err
+	}
+	return RandomToKey(r), nil
+}
+
+// DeriveRandom derives the random data used for key generation (DR in RFC
+// 6803 section 3), using the SP800-108 feedback-mode KDF with CMAC as the
+// PRF: K(i) = CMAC(key, K(i-1) || i (4-octet big-endian counter) || label ||
+// 0x00). There is no n-fold step; the label is fed to CMAC in full.
+func DeriveRandom(protocolKey, label []byte, e etype.EType) ([]byte, error) {
+	c, err := camellia.NewCipher(protocolKey)
+	if err != nil {
+		return nil, err
+	}
+	var out, kPrev []byte
+	for i := uint32(1); len(out) < e.GetKeyByteSize(); i++ {
+		input := make([]byte, 0, len(kPrev)+4+len(label)+1)
+		input = append(input, kPrev...)
+		input = append(input, byte(i>>24), byte(i>>16), byte(i>>8), byte(i))
+		input = append(input, label...)
+		input = append(input, 0x00)
+		kPrev = cmac(c, input)
+		out = append(out, kPrev...)
+	}
+	return out[:e.GetKeyByteSize()], nil
+}
+
+// GetChecksumHash returns the CMAC-camellia checksum of data keyed with protocolKey,
+// as required by the cmac-camellia128/cmac-camellia256 checksum types.
+func GetChecksumHash(protocolKey, data []byte, usage uint32, e etype.EType) ([]byte, error) {
+	kc, err := DeriveKey(protocolKey, usageKc(usage), e)
+	if err != nil {
+		return nil, err
+	}
+	c, err := camellia.NewCipher(kc)
+	if err != nil {
+		return nil, err
+	}
+	return cmac(c, data), nil
+}
+
+// defaultIterations is the iteration count used when s2kparams is empty,
+// matching Camellia128CtsCmac/Camellia256CtsCmac's GetDefaultStringToKeyParams
+// ("00008000" = 32768).
+const defaultIterations = 32768
+
+func iterations(s2kparams string) (int, error) {
+	if len(s2kparams) == 0 {
+		return defaultIterations, nil
+	}
+	if len(s2kparams) != 8 {
+		return 0, errors.New("invalid s2kparams length")
+	}
+	var i uint32
+	for _, c := range s2kparams {
+		v, err := hexVal(byte(c))
+		if err != nil {
+			return 0, err
+		}
+		i = i<<4 | uint32(v)
+	}
+	return int(i), nil
+}
+
+func hexVal(c byte) (byte, error) {
+	switch {
+	case c >= '0' && c <= '9':
+		return c - '0', nil
+	case c >= 'a' && c <= 'f':
+		return c - 'a' + 10, nil
+	case c >= 'A' && c <= 'F':
+		return c - 'A' + 10, nil
+	}
+	return 0, errors.New("invalid hex digit in s2kparams")
+}
+
+// usageKc derives the checksum key usage number for the given Kerberos key usage,
+// following the convention in RFC 3961 section 5.
+func usageKc(usage uint32) []byte {
+	return []byte{byte(usage >> 24), byte(usage >> 16), byte(usage >> 8), byte(usage), 0x99}
+}
@@ -0,0 +1,129 @@
+// Package rfc8009 implements the AES-CTS-HMAC-SHA2 encryption types for
+// Kerberos 5 defined in RFC 8009: aes128-cts-hmac-sha256-128 and
+// aes256-cts-hmac-sha384-192.
+//
+// Unlike RFC 6803's feedback-mode KDF, RFC 8009 section 3 derives keys with
+// the SP800-108 KDF in counter mode, using HMAC-SHA2 as the PRF:
+// K(i) = HMAC-SHA2(key, i (4-octet big-endian counter) || label || 0x00 ||
+// k (4-octet big-endian output length in bits)), with no feedback between
+// blocks. random-to-key is the identity function.
+//
+// The AES ciphers, the HMACs of the KDF and integrity hash, and the SHA-2
+// hash used by string-to-key are obtained from the currently registered
+// crypto/provider.Provider, so a FIPS/PKCS#11 backend registered via
+// provider.SetProvider actually takes effect for this etype. The HMAC that
+// PBKDF2 applies over that hash is golang.org/x/crypto/pbkdf2's own.
+package rfc8009
+
+import (
+	"encoding/binary"
+	"errors"
+	"hash"
+
+	"golang.org/x/crypto/pbkdf2"
+
+	"gopkg.in/burningass23/gokrb5.v7/crypto/etype"
+	"gopkg.in/burningass23/gokrb5.v7/crypto/provider"
+	"gopkg.in/burningass23/gokrb5.v7/iana/etypeID"
+)
+
+const kerberosConstant = "kerberos"
+
+// GetSaltP returns the salt string used for string-to-key, which RFC 8009
+// section 4 prefixes with the etype name and a NUL byte, unlike RFC 6803's bare salt.
+func GetSaltP(salt, etypeName string) string {
+	return etypeName + "\x00" + salt
+}
+
+// StringToKey returns a key derived from the string provided, per RFC 8009 section 4.
+func StringToKey(secret, saltp, s2kparams string, e etype.EType) ([]byte, error) {
+	i, err := iterations(s2kparams)
+	if err != nil {
+		return nil, err
+	}
+	newHash := func() hash.Hash { return provider.Current().NewHash(e.GetHashFunc()) }
+	tkey := pbkdf2.Key([]byte(secret), []byte(saltp), i, e.GetKeyByteSize(), newHash)
+	return DeriveKey(tkey, []byte(kerberosConstant), e)
+}
+
+// RandomToKey returns a key from the bytes provided. RFC 8009 defines
+// random-to-key as the identity function.
+func RandomToKey(b []byte) []byte {
+	return b
+}
+
+// DeriveKey derives a key from the protocol key and usage value (DK in RFC 3961/8009).
+func DeriveKey(protocolKey, usage []byte, e etype.EType) ([]byte, error) {
+	r, err := DeriveRandom(protocolKey, usage, e)
+	if err != nil {
+		return nil, err
+	}
+	return RandomToKey(r), nil
+}
+
+// DeriveRandom derives the random data used for key generation (DR in RFC
+// 8009 section 3), using the SP800-108 counter-mode KDF with HMAC-SHA2 as
+// the PRF: K(i) = HMAC-SHA2(key, i (4-octet big-endian counter) || label ||
+// 0x00 || k (4-octet big-endian output length in bits)). Unlike RFC 6803's
+// feedback-mode DR, there is no K(i-1) term.
+func DeriveRandom(protocolKey, label []byte, e etype.EType) ([]byte, error) {
+	outLen := keyLength(label, e)
+	kBits := make([]byte, 4)
+	binary.BigEndian.PutUint32(kBits, uint32(outLen*8))
+
+	var out []byte
+	for i := uint32(1); len(out) < outLen; i++ {
+		h := provider.Current().NewHMAC(protocolKey, e.GetHashFunc())
+		var ctr [4]byte
+		binary.BigEndian.PutUint32(ctr[:], i)
+		h.Write(ctr[:])
+		h.Write(label)
+		h.Write([]byte{0x00})
+		h.Write(kBits)
+		out = append(out, h.Sum(nil)...)
+	}
+	return out[:outLen], nil
+}
+
+// keyLength returns the length in bytes of a key derived with label. For
+// aes256-cts-hmac-sha384-192 the base key (label "kerberos") and the
+// encryption key Ke (label ending 0xAA) are 256 bits, while Ki and Kc keep
+// GetKeyByteSize's 192 bits; other etypes use GetKeyByteSize throughout.
+func keyLength(label []byte, e etype.EType) int {
+	if e.GetETypeID() == etypeID.AES256_CTS_HMAC_SHA384_192 &&
+		(string(label) == kerberosConstant || (len(label) > 0 && label[len(label)-1] == keyUsageEncrypt)) {
+		return 32
+	}
+	return e.GetKeyByteSize()
+}
+
+func iterations(s2kparams string) (int, error) {
+	const defaultIterations = 32768
+	if len(s2kparams) == 0 {
+		return defaultIterations, nil
+	}
+	if len(s2kparams) != 8 {
+		return 0, errors.New("invalid s2kparams length")
+	}
+	var i uint32
+	for _, c := range s2kparams {
+		v, err := hexVal(byte(c))
+		if err != nil {
+			return 0, err
+		}
+		i = i<<4 | uint32(v)
+	}
+	return int(i), nil
+}
+
+func hexVal(c byte) (byte, error) {
+	switch {
+	case c >= '0' && c <= '9':
+		return c - '0', nil
+	case c >= 'a' && c <= 'f':
+		return c - 'a' + 10, nil
+	case c >= 'A' && c <= 'F':
+		return c - 'A' + 10, nil
+	}
+	return 0, errors.New("invalid hex digit in s2kparams")
+}
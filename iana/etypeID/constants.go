@@ -0,0 +1,29 @@
+// Package etypeID provides Kerberos encryption type assigned numbers.
+package etypeID
+
+// Encryption type IDs.
+const (
+	RESERVED                     = 0
+	DES_CBC_CRC                  = 1
+	DES_CBC_MD4                  = 2
+	DES_CBC_MD5                  = 3
+	DES3_CBC_MD5                 = 5
+	DES3_CBC_SHA1                = 7
+	DSAWITHSHA1_CMSOID           = 9
+	MD5WITHRSAENCRYPTION_CMSOID  = 10
+	SHA1WITHRSAENCRYPTION_CMSOID = 11
+	RC2CBC_ENVOID                = 12
+	RSAENCRYPTION_ENVOID         = 13
+	RSAES_OAEP_ENV_OID           = 14
+	DES_EDE3_CBC_ENV_OID         = 15
+	DES3_CBC_SHA1_KD             = 16
+	AES128_CTS_HMAC_SHA1_96      = 17
+	AES256_CTS_HMAC_SHA1_96      = 18
+	AES128_CTS_HMAC_SHA256_128   = 19
+	AES256_CTS_HMAC_SHA384_192   = 20
+	RC4_HMAC                     = 23
+	RC4_HMAC_EXP                 = 24
+	CAMELLIA128_CTS_CMAC         = 25
+	CAMELLIA256_CTS_CMAC         = 26
+	SUBKEY_KEYMATERIAL           = 65
+)